@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github/greenlight/internal/data"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/cache"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// wrapMoviesWithCache decorates store with a Redis-backed read-through
+// cache when -cache=readthrough, per app.config.redis.
+func (app *application) wrapMoviesWithCache(store data.MovieStore) data.MovieStore {
+	client := redis.NewClient(&redis.Options{
+		Addr:     app.config.redis.addr,
+		Password: app.config.redis.password,
+		DB:       app.config.redis.db,
+	})
+
+	return data.NewCachedMovieModel(store, cache.NewRedisCache(client), app.config.redis.ttl)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+type config struct {
+	port int
+	env  string
+	db   struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  string
+	}
+	smtp struct {
+		host     string
+		port     int
+		username string
+		password string
+		sender   string
+	}
+	migrate struct {
+		cmd string
+		arg string
+	}
+	cache string
+	redis struct {
+		addr     string
+		password string
+		db       int
+		ttl      time.Duration
+	}
+	encryptionKeys string
+	emailHashKey   string
+	reencrypt      bool
+}
+
+func getConfig() *config {
+	var cfg config
+
+	displayVersion := flag.Bool("version", false, "Display version and exit")
+
+	flag.IntVar(&cfg.port, "port", 4000, "API server port")
+	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+
+	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("GREENLIGHT_DB_DSN"), "PostgreSQL DSN")
+	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
+	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
+	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+
+	flag.StringVar(&cfg.smtp.host, "smtp-host", "smtp.mailtrap.io", "SMTP host")
+	flag.IntVar(&cfg.smtp.port, "smtp-port", 25, "SMTP port")
+	flag.StringVar(&cfg.smtp.username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.smtp.password, "smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Greenlight <no-reply@greenlight.example.com>", "SMTP sender")
+
+	flag.StringVar(&cfg.migrate.cmd, "migrate", "", "Run a migration command and exit (up|down|version|force) instead of starting the server")
+	flag.StringVar(&cfg.migrate.arg, "migrate-arg", "", "Argument for the migrate command (e.g. the N in force N)")
+
+	flag.StringVar(&cfg.cache, "cache", "off", "Movie cache mode (off|readthrough)")
+	flag.StringVar(&cfg.redis.addr, "redis-addr", "localhost:6379", "Redis address")
+	flag.StringVar(&cfg.redis.password, "redis-password", "", "Redis password")
+	flag.IntVar(&cfg.redis.db, "redis-db", 0, "Redis database number")
+	flag.DurationVar(&cfg.redis.ttl, "redis-ttl", 5*time.Minute, "Movie cache entry TTL")
+
+	flag.StringVar(&cfg.encryptionKeys, "encryption-keys", os.Getenv("GREENLIGHT_ENCRYPTION_KEYS"), "Comma-separated base64 AES-256 keys for PII encryption, newest last")
+	flag.StringVar(&cfg.emailHashKey, "email-hash-key", os.Getenv("GREENLIGHT_EMAIL_HASH_KEY"), "Base64 HMAC key for the users.email_hash lookup column; must stay stable across -encryption-keys rotations")
+	flag.BoolVar(&cfg.reencrypt, "reencrypt", false, "Rewrite every user row under the current encryption key and exit instead of starting the server")
+
+	flag.Parse()
+
+	if *displayVersion {
+		fmt.Printf("Version:\t%s\n", version)
+		os.Exit(0)
+	}
+
+	return &cfg
+}
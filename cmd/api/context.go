@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github/greenlight/internal/data"
+)
+
+type contextKey string
+
+const userContextKey = contextKey("user")
+
+// contextSetUser returns a copy of r with user attached, for handlers and
+// middleware further down the chain (e.g. idempotencyKey) to read back via
+// contextGetUser.
+func (app application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+}
+
+// contextGetUser returns the user attached to r by contextSetUser, or
+// data.AnonymousUser if none was set. This snapshot has no authenticate
+// middleware wired in yet, so unlike the authenticated-user accessor this
+// is modelled on, it deliberately falls back instead of panicking - every
+// route (including the unauthenticated create endpoints wired in
+// routes()) must still be able to call it safely.
+func (app application) contextGetUser(r *http.Request) *data.User {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	if !ok {
+		return data.AnonymousUser
+	}
+	return user
+}
@@ -115,16 +115,3 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	}
 	return num
 }
-
-func (app *application) background(fn func()) {
-	app.wg.Add(1)
-	go func() {
-		defer app.wg.Done()
-		defer func() {
-			if err := recover(); err != nil {
-				app.logger.PrintError(fmt.Errorf("%s", err), nil)
-			}
-		}()
-		fn()
-	}()
-}
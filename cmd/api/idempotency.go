@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// idempotencyKeyTTL is how long a stored response is replayed for before
+// the sweeper removes it and a retried request is treated as new.
+const idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyPendingStatus is the response_status sentinel for a row
+// that's been claimed but whose handler hasn't finished yet. No real HTTP
+// status is 0, so it can't collide with a stored response.
+const idempotencyPendingStatus = 0
+
+// idempotencyReplaysServed counts requests short-circuited with a
+// previously stored response.
+var idempotencyReplaysServed = expvar.NewInt("idempotency_replays_served")
+
+// idempotencyRecorder captures the status, headers and body a handler
+// writes, so they can be persisted alongside the request's fingerprint.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotencyKey makes POST handlers safe to retry; see routes() for where
+// it's wired in around the create endpoints.
+//
+// A client-supplied Idempotency-Key header, combined with the
+// authenticated user and the request path, claims a row in
+// idempotency_keys before the handler runs, so two concurrent requests for
+// the same key (the dropped-connection-retry case this exists for) can't
+// both execute the handler: the loser gets back a 409 telling it the
+// original request is still in flight. Once the handler finishes, its
+// response is stored against the claimed row and replayed verbatim for
+// any later retry. A retry whose request body/method/path don't match the
+// original is rejected with 422, since replaying it would silently return
+// the wrong response for a different request.
+func (app *application) idempotencyKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idemKey := r.Header.Get("Idempotency-Key")
+		if idemKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user := app.contextGetUser(r)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		keyHash := sha256.Sum256(fmt.Appendf(nil, "%s:%d:%s", idemKey, user.ID, r.URL.Path))
+		fingerprint := sha256.Sum256(fmt.Appendf(nil, "%s:%s:%s", r.Method, r.URL.Path, body))
+
+		claimed, existing, err := app.claimIdempotencyKey(keyHash[:], user.ID, fingerprint[:])
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !claimed {
+			if !bytes.Equal(existing.requestFingerprint, fingerprint[:]) {
+				app.writeIdempotencyConflict(w)
+				return
+			}
+			if existing.responseStatus == idempotencyPendingStatus {
+				app.writeIdempotencyInProgress(w)
+				return
+			}
+			idempotencyReplaysServed.Add(1)
+			app.replayIdempotencyRecord(w, existing)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		headers, err := json.Marshal(rec.Header())
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		err = app.completeIdempotencyKey(keyHash[:], rec.status, headers, rec.body.Bytes())
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
+
+func (app *application) writeIdempotencyConflict(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	w.Write([]byte(`{"error":"Idempotency-Key was already used for a different request"}`))
+}
+
+func (app *application) writeIdempotencyInProgress(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	w.Write([]byte(`{"error":"a request with this Idempotency-Key is still being processed"}`))
+}
+
+func (app *application) replayIdempotencyRecord(w http.ResponseWriter, record *idempotencyRecord) {
+	var headers http.Header
+	if err := json.Unmarshal(record.responseHeadersJSON, &headers); err != nil {
+		app.logger.PrintError(err, nil)
+	}
+	for k, v := range headers {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(record.responseStatus)
+	w.Write(record.responseBody)
+}
+
+type idempotencyRecord struct {
+	userID              int64
+	requestFingerprint  []byte
+	responseStatus      int
+	responseHeadersJSON []byte
+	responseBody        []byte
+}
+
+// claimIdempotencyKey atomically reserves keyHash for this request before
+// the handler runs. It reports claimed=true if the caller owns the key and
+// should run the handler (a fresh row, or a reclaimed one whose expires_at
+// had already passed but the hourly sweeper hadn't deleted it yet). When
+// claimed is false, existing holds the row the caller lost the race to
+// (either another in-flight request, or a previously completed one to
+// replay/reject).
+func (app *application) claimIdempotencyKey(keyHash []byte, userID int64, fingerprint []byte) (claimed bool, existing *idempotencyRecord, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	expiresAt := time.Now().Add(idempotencyKeyTTL)
+
+	res, err := app.jobs.DB.ExecContext(ctx, `
+		INSERT INTO idempotency_keys
+		(key_hash, user_id, request_fingerprint, response_status, response_headers, response_body, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, '{}'::jsonb, ''::bytea, NOW(), $5)
+		ON CONFLICT (key_hash) DO NOTHING`,
+		keyHash, userID, fingerprint, idempotencyPendingStatus, expiresAt)
+	if err != nil {
+		return false, nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, nil, err
+	} else if n == 1 {
+		return true, nil, nil
+	}
+
+	// Someone already holds this key. If their row has already expired
+	// (expired but not yet swept), reclaim it for this request instead of
+	// treating it as a live conflict - otherwise every retry during that
+	// window would fail to ever persist its response.
+	reclaimCtx, reclaimCancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer reclaimCancel()
+	res, err = app.jobs.DB.ExecContext(reclaimCtx, `
+		UPDATE idempotency_keys
+		SET user_id = $2, request_fingerprint = $3, response_status = $4,
+		    response_headers = '{}'::jsonb, response_body = ''::bytea,
+		    created_at = NOW(), expires_at = $5
+		WHERE key_hash = $1 AND expires_at <= NOW()`,
+		keyHash, userID, fingerprint, idempotencyPendingStatus, expiresAt)
+	if err != nil {
+		return false, nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, nil, err
+	} else if n == 1 {
+		return true, nil, nil
+	}
+
+	record, err := app.getIdempotencyRecord(keyHash)
+	if err != nil {
+		return false, nil, err
+	}
+	return false, record, nil
+}
+
+func (app *application) getIdempotencyRecord(keyHash []byte) (*idempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var record idempotencyRecord
+	stmt := `SELECT user_id, request_fingerprint, response_status, response_headers, response_body
+	FROM idempotency_keys
+	WHERE key_hash = $1`
+	err := app.jobs.DB.QueryRowContext(ctx, stmt, keyHash).Scan(
+		&record.userID,
+		&record.requestFingerprint,
+		&record.responseStatus,
+		&record.responseHeadersJSON,
+		&record.responseBody,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// completeIdempotencyKey fills in the real response on a row previously
+// reserved by claimIdempotencyKey, so later retries can replay it.
+func (app *application) completeIdempotencyKey(keyHash []byte, status int, headers, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := app.jobs.DB.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET response_status = $2, response_headers = $3, response_body = $4
+		WHERE key_hash = $1`,
+		keyHash, status, headers, body)
+	return err
+}
+
+// sweepIdempotencyKeys deletes expired records. It's run periodically from
+// main so replayed keys don't accumulate forever.
+func (app *application) sweepIdempotencyKeys() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := app.jobs.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	return err
+}
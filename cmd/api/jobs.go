@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/jobs"
+)
+
+const (
+	jobKindSendActivationEmail = "send_activation_email"
+	jobKindSendResetEmail      = "send_reset_email"
+)
+
+// activationEmailPayload is the jobs.payload body for a
+// jobKindSendActivationEmail job.
+type activationEmailPayload struct {
+	Email           string `json:"email"`
+	UserID          int64  `json:"user_id"`
+	ActivationToken string `json:"activation_token"`
+}
+
+// resetEmailPayload is the jobs.payload body for a jobKindSendResetEmail
+// job.
+type resetEmailPayload struct {
+	Email      string `json:"email"`
+	ResetToken string `json:"reset_token"`
+}
+
+// registerJobHandlers wires up the per-kind handlers that worker goroutines
+// dispatch claimed jobs to.
+func (app *application) registerJobHandlers(worker *jobs.Worker) {
+	worker.Register(jobKindSendActivationEmail, func(ctx context.Context, payload json.RawMessage) error {
+		var p activationEmailPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		return app.mailer.Send(p.Email, "user_welcome.tmpl", p)
+	})
+
+	worker.Register(jobKindSendResetEmail, func(ctx context.Context, payload json.RawMessage) error {
+		var p resetEmailPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		return app.mailer.Send(p.Email, "token_password_reset.tmpl", p)
+	})
+}
@@ -12,8 +12,10 @@ import (
 	"sync"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"               // New import
-	_ "github.com/golang-migrate/migrate/v4/source/file" // New import
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/crypto"
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/jobs"
+
+	"github.com/golang-migrate/migrate/v4"
 
 	_ "github.com/lib/pq"
 )
@@ -22,16 +24,20 @@ var (
 	version = vcs.Version()
 )
 
+// jobWorkerCount is the number of goroutines polling the job queue.
+const jobWorkerCount = 5
+
 type application struct {
 	config config
 	logger *jsonlog.Logger
 	models data.Model
 	mailer mailer.Mailer
+	jobs   *jobs.JobQueue
+	worker *jobs.Worker
 	wg     *sync.WaitGroup
 }
 
 func main() {
-	checkVersion()
 	cfg := getConfig()
 	logger := jsonlog.NewLogger(os.Stdout, jsonlog.LevelInfo)
 	db, err := openDB(cfg)
@@ -42,29 +48,93 @@ func main() {
 
 	logger.PrintInfo("database connection pool established", nil)
 
+	if cfg.migrate.cmd != "" {
+		app := application{config: *cfg, logger: logger}
+		err = app.runMigrateCommand(db, cfg.migrate.cmd, cfg.migrate.arg)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		return
+	}
+
+	keyring, err := crypto.NewKeyringFromEnv(cfg.encryptionKeys, cfg.emailHashKey)
 	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 
+	if cfg.reencrypt {
+		users := data.UserModel{DB: db, Keyring: keyring}
+		rewritten, err := users.Reencrypt(100)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		logger.PrintInfo("reencrypt complete", map[string]string{
+			"rows_rewritten": fmt.Sprintf("%d", rewritten),
+		})
+		return
+	}
+
+	jobQueue := jobs.NewQueue(db)
+
 	app := application{
 		config: *cfg,
 		logger: logger,
-		models: data.NewModel(db),
+		models: data.NewModel(db, keyring),
 		mailer: mailer.New(
 			cfg.smtp.host,
 			cfg.smtp.port,
 			cfg.smtp.username,
 			cfg.smtp.password,
 			cfg.smtp.sender),
-		wg: &sync.WaitGroup{},
+		jobs: jobQueue,
+		wg:   &sync.WaitGroup{},
+	}
+
+	app.worker = jobs.NewWorker(jobQueue, logger, fmt.Sprintf("worker-%d", os.Getpid()))
+	app.registerJobHandlers(app.worker)
+
+	if cfg.cache == "readthrough" {
+		app.models.Movies = app.wrapMoviesWithCache(app.models.Movies)
 	}
 
-	SetupMetric(&app)
+	SetupMetric(&app, db)
 
+	// Must run before anything starts polling the jobs/idempotency_keys
+	// tables below, or a fresh database's first deploy has the worker and
+	// sweeper hammering tables that don't exist yet.
 	err = app.migrateDb(db)
 	if err != nil && err != migrate.ErrNoChange {
 		logger.PrintFatal(err, nil)
 	}
+
+	// GetByEmail looks up exclusively by email_hash, so any row migration
+	// 000007 left with a NULL hash is invisible to it until -reencrypt
+	// rewrites it. Refuse to serve traffic on a half-migrated users table
+	// rather than silently locking out whatever's left.
+	unhashed, err := data.UserModel{DB: db}.CountUnhashed()
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	if unhashed > 0 {
+		logger.PrintFatal(fmt.Errorf("%d users row(s) have no email_hash; run -reencrypt before starting the server", unhashed), nil)
+	}
+
+	app.worker.Start(jobWorkerCount)
+
+	// Runs for the lifetime of the process; not tracked via app.wg since a
+	// missed final sweep on shutdown is harmless (expired keys are swept by a
+	// later tick of this loop, and claimIdempotencyKey reclaims an expired
+	// row it runs into before then).
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := app.sweepIdempotencyKeys(); err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	}()
+
 	err = app.server()
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"database/sql"
 	"expvar"
 	"runtime"
 	"time"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/data"
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/jobs"
 )
 
-func SetupMetric(app *application) {
+func SetupMetric(app *application, db *sql.DB) {
 	expvar.NewString("version").Set(version)
 	// Publish the number of active goroutines.
 	expvar.Publish("goroutines", expvar.Func(func() any {
@@ -14,11 +18,18 @@ func SetupMetric(app *application) {
 	}))
 	// Publish the database connection pool statistics.
 	expvar.Publish("database", expvar.Func(func() any {
-		return app.models.Movies.DB.Stats()
+		return db.Stats()
 	}))
 	// Publish the current Unix timestamp.
 	expvar.Publish("timestamp", expvar.Func(func() any {
 		return time.Now().Unix()
 	}))
 
+	// Publish the job queue counters maintained by internal/jobs.
+	expvar.Publish("jobs_processed", jobs.Processed)
+	expvar.Publish("jobs_failed", jobs.Failed)
+	expvar.Publish("jobs_in_flight", jobs.InFlight)
+
+	// Publish the movie cache error counter maintained by internal/data.
+	expvar.Publish("cache_errors", data.CacheErrors)
 }
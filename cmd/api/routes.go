@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// routes wires the create endpoints the idempotencyKey middleware exists
+// for - a dropped connection during POST /v1/movies or POST /v1/users can
+// otherwise leave the client unsure whether its request landed, and retry
+// into a duplicate.
+func (app application) routes() http.Handler {
+	router := httprouter.New()
+
+	router.Handler(http.MethodPost, "/v1/movies", app.idempotencyKey(http.HandlerFunc(app.createMovieHandler)))
+	router.Handler(http.MethodPost, "/v1/users", app.idempotencyKey(http.HandlerFunc(app.registerUserHandler)))
+
+	return router
+}
@@ -9,12 +9,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/Ahmed-Abdel-karim/greenlight/migrations"
+
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file" // New import
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
 func (app application) server() error {
@@ -49,6 +52,15 @@ func (app application) server() error {
 			"addr": srv.Addr,
 		})
 		app.wg.Wait()
+
+		if app.worker != nil {
+			if err := app.worker.Shutdown(ctx); err != nil {
+				app.logger.PrintInfo("job worker shutdown timed out, in-flight jobs left locked for reclaim", map[string]string{
+					"error": err.Error(),
+				})
+			}
+		}
+
 		shutdownError <- nil
 	}()
 
@@ -74,15 +86,67 @@ func (app application) server() error {
 	return nil
 }
 
-func (app application) migrateDb(db *sql.DB) error {
+// newMigrator builds a golang-migrate Migrator that reads its migration
+// files out of the binary (via migrations.FS) instead of the filesystem, so
+// the greenlight binary can migrate its own schema with nothing else
+// shipped alongside it.
+func (app application) newMigrator(db *sql.DB) (*migrate.Migrate, error) {
 	migrationDriver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		app.logger.PrintFatal(err, nil)
+		return nil, err
 	}
 
-	migrator, err := migrate.NewWithDatabaseInstance("file://migrations", "postgres", migrationDriver)
+	sourceDriver, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", sourceDriver, "postgres", migrationDriver)
+}
+
+func (app application) migrateDb(db *sql.DB) error {
+	migrator, err := app.newMigrator(db)
 	if err != nil {
 		return err
 	}
 	return migrator.Up()
 }
+
+// runMigrateCommand handles the operator-facing `-migrate` subcommands
+// (up|down|version|force). It runs against the embedded migrations and
+// never starts the HTTP server.
+func (app application) runMigrateCommand(db *sql.DB, cmd, arg string) error {
+	migrator, err := app.newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "version":
+		var version uint
+		version, _, err = migrator.Version()
+		if err == nil {
+			app.logger.PrintInfo("migration version", map[string]string{
+				"version": strconv.FormatUint(uint64(version), 10),
+			})
+		}
+	case "force":
+		var n int
+		n, err = strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("force requires a numeric version, got %q", arg)
+		}
+		err = migrator.Force(n)
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up|down|version|force)", cmd)
+	}
+
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/data"
+)
+
+const exportPageSize = 100
+
+func runMoviesExport(args []string) error {
+	fs := flag.NewFlagSet("movies export", flag.ExitOnError)
+	dsn := dsnFlag(fs)
+	format := fs.String("format", "json", "Export format (json|ndjson|csv)")
+	out := fs.String("out", "", "Output file path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("export: --out is required")
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writeHeader, writeRow, writeFooter, err := exportWriters(*format, f)
+	if err != nil {
+		return err
+	}
+
+	movies := data.MovieModel{DB: db}
+
+	if err := writeHeader(); err != nil {
+		return err
+	}
+
+	total := 0
+	page := 1
+	first := true
+	for {
+		filters := data.Filters{Page: page, PageSize: exportPageSize, Sort: "id", SortSafelist: []string{"id", "-id"}}
+		batch, metadata, err := movies.GetAll("", nil, filters)
+		if err != nil {
+			return err
+		}
+		for _, movie := range batch {
+			if err := writeRow(movie, first); err != nil {
+				return err
+			}
+			first = false
+			total++
+		}
+		if metadata.LastPage == 0 || page >= metadata.LastPage {
+			break
+		}
+		page++
+	}
+
+	if err := writeFooter(); err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d movies to %s\n", total, *out)
+	return nil
+}
+
+// exportWriters returns the header/row/footer functions for the requested
+// format, so runMoviesExport can stream rows out page-by-page instead of
+// holding the whole table in memory.
+func exportWriters(format string, f *os.File) (header func() error, row func(data.Movie, bool) error, footer func() error, err error) {
+	switch format {
+	case "json":
+		header = func() error { _, err := f.WriteString("[\n"); return err }
+		row = func(m data.Movie, first bool) error {
+			if !first {
+				if _, err := f.WriteString(",\n"); err != nil {
+					return err
+				}
+			}
+			b, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+			_, err = f.Write(b)
+			return err
+		}
+		footer = func() error { _, err := f.WriteString("\n]\n"); return err }
+		return header, row, footer, nil
+
+	case "ndjson":
+		enc := json.NewEncoder(f)
+		header = func() error { return nil }
+		row = func(m data.Movie, _ bool) error { return enc.Encode(m) }
+		footer = func() error { return nil }
+		return header, row, footer, nil
+
+	case "csv":
+		w := csv.NewWriter(f)
+		header = func() error {
+			return w.Write([]string{"id", "title", "year", "runtime", "genres", "version"})
+		}
+		row = func(m data.Movie, _ bool) error {
+			return w.Write([]string{
+				strconv.FormatInt(m.ID, 10),
+				m.Title,
+				strconv.Itoa(int(m.Year)),
+				strconv.Itoa(int(m.Runtime)),
+				strings.Join(m.Genres, "|"),
+				strconv.Itoa(int(m.Version)),
+			})
+		}
+		footer = func() error {
+			w.Flush()
+			return w.Error()
+		}
+		return header, row, footer, nil
+
+	default:
+		return nil, nil, nil, fmt.Errorf("export: unknown format %q (want json|ndjson|csv)", format)
+	}
+}
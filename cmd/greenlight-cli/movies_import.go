@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/data"
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/validator"
+)
+
+type importSummary struct {
+	inserted, updated, skipped, failed int
+}
+
+func runMoviesImport(args []string) error {
+	fs := flag.NewFlagSet("movies import", flag.ExitOnError)
+	dsn := dsnFlag(fs)
+	format := fs.String("format", "json", "Import format (json|ndjson|csv)")
+	in := fs.String("in", "", "Input file path")
+	upsert := fs.Bool("upsert", false, "Update existing rows matched on (title, year) instead of skipping them")
+	continueOnError := fs.Bool("continue-on-error", false, "Keep importing after a validation failure instead of exiting non-zero")
+	batchSize := fs.Int("batch-size", 100, "Number of rows inserted per transaction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("import: --in is required")
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	f, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	next, err := importReader(*format, f)
+	if err != nil {
+		return err
+	}
+
+	summary := importSummary{}
+	var batch []data.Movie
+	var batchLines []int
+	lineNum := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := importBatch(db, batch, batchLines, *upsert, &summary); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		batchLines = batchLines[:0]
+		return nil
+	}
+
+	for {
+		movie, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		lineNum++
+
+		v := validator.New()
+		data.ValidateMovie(v, &movie)
+		if !v.Valid() {
+			summary.failed++
+			fmt.Fprintf(os.Stderr, "line %d: invalid movie: %v\n", lineNum, v.Errors)
+			if !*continueOnError {
+				return fmt.Errorf("import: validation failed at line %d (use --continue-on-error to keep going)", lineNum)
+			}
+			continue
+		}
+
+		batch = append(batch, movie)
+		batchLines = append(batchLines, lineNum)
+		if len(batch) >= *batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("inserted=%d updated=%d skipped=%d failed=%d\n", summary.inserted, summary.updated, summary.skipped, summary.failed)
+	if summary.failed > 0 && !*continueOnError {
+		return fmt.Errorf("import: %d row(s) failed validation", summary.failed)
+	}
+	return nil
+}
+
+// importBatch writes one batch inside a single transaction, so a failure
+// partway through doesn't leave the table half-updated.
+func importBatch(db *sql.DB, batch []data.Movie, lines []int, upsert bool, summary *importSummary) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	movies := data.MovieModel{DB: tx}
+
+	for i, movie := range batch {
+		existing, err := movies.GetByTitleAndYear(movie.Title, movie.Year)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			if err := movies.Insert(&movie); err != nil {
+				return fmt.Errorf("line %d: %w", lines[i], err)
+			}
+			summary.inserted++
+
+		case err != nil:
+			return fmt.Errorf("line %d: %w", lines[i], err)
+
+		case !upsert:
+			summary.skipped++
+
+		default:
+			movie.ID = existing.ID
+			movie.Version = existing.Version
+			if err := movies.Update(&movie); err != nil {
+				return fmt.Errorf("line %d: %w", lines[i], err)
+			}
+			summary.updated++
+		}
+	}
+
+	return tx.Commit()
+}
+
+// importReader returns a Next() func yielding one data.Movie at a time from
+// f, decoded according to format.
+func importReader(format string, f *os.File) (func() (data.Movie, bool, error), error) {
+	switch format {
+	case "json":
+		dec := json.NewDecoder(f)
+		if _, err := dec.Token(); err != nil { // consume the opening '['
+			return nil, err
+		}
+		return func() (data.Movie, bool, error) {
+			if !dec.More() {
+				return data.Movie{}, false, nil
+			}
+			var m data.Movie
+			if err := dec.Decode(&m); err != nil {
+				return data.Movie{}, false, err
+			}
+			return m, true, nil
+		}, nil
+
+	case "ndjson":
+		scanner := bufio.NewScanner(f)
+		return func() (data.Movie, bool, error) {
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" {
+					continue
+				}
+				var m data.Movie
+				if err := json.Unmarshal([]byte(line), &m); err != nil {
+					return data.Movie{}, false, err
+				}
+				return m, true, nil
+			}
+			return data.Movie{}, false, scanner.Err()
+		}, nil
+
+	case "csv":
+		r := csv.NewReader(f)
+		if _, err := r.Read(); err != nil { // header row
+			return nil, err
+		}
+		return func() (data.Movie, bool, error) {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				return data.Movie{}, false, nil
+			}
+			if err != nil {
+				return data.Movie{}, false, err
+			}
+			m, err := movieFromCSV(record)
+			return m, true, err
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("import: unknown format %q (want json|ndjson|csv)", format)
+	}
+}
+
+// movieFromCSV parses a row written by exportWriters' csv writer:
+// id,title,year,runtime,genres,version. id/version are ignored on import.
+func movieFromCSV(record []string) (data.Movie, error) {
+	if len(record) != 6 {
+		return data.Movie{}, fmt.Errorf("expected 6 columns, got %d", len(record))
+	}
+	year, err := strconv.Atoi(record[2])
+	if err != nil {
+		return data.Movie{}, fmt.Errorf("invalid year %q: %w", record[2], err)
+	}
+	runtime, err := strconv.Atoi(record[3])
+	if err != nil {
+		return data.Movie{}, fmt.Errorf("invalid runtime %q: %w", record[3], err)
+	}
+
+	var genres []string
+	if record[4] != "" {
+		genres = strings.Split(record[4], "|")
+	}
+
+	return data.Movie{
+		Title:   record[1],
+		Year:    int32(year),
+		Runtime: data.Runtime(runtime),
+		Genres:  genres,
+	}, nil
+}
@@ -0,0 +1,25 @@
+// Package cache provides a small read-through cache abstraction so callers
+// (currently internal/data's movie decorator) aren't coupled to a specific
+// cache backend.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is a tagged key/value store. Tags let a whole group of keys (e.g.
+// every cached movie listing page) be invalidated together without knowing
+// their individual keys.
+type Cache interface {
+	// Get returns the cached value for key. The second return value
+	// reports whether key was present.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl, associating it with tags so it
+	// can later be removed via DeleteByTag.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// Delete removes a single key.
+	Delete(ctx context.Context, key string) error
+	// DeleteByTag removes every key that was Set with the given tag.
+	DeleteByTag(ctx context.Context, tag string) error
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache is a Cache backed by a single Redis instance. Tags are
+// implemented as Redis sets of member keys (tag:{tag} -> {key, key, ...}),
+// so DeleteByTag is a SMEMBERS followed by a DEL of the returned keys plus
+// the tag set itself.
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache returns a RedisCache using client.
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+func tagKey(tag string) string {
+	return "tag:" + tag
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if err := c.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		tk := tagKey(tag)
+		if err := c.client.SAdd(ctx, tk, key).Err(); err != nil {
+			return err
+		}
+		// Tag sets have no natural expiry of their own, so a read-heavy,
+		// write-light tag would otherwise accumulate every distinct key
+		// ever added to it. Keep the set's TTL no shorter than its
+		// longest-lived member so it's eventually reaped between writes,
+		// without cutting short a tag set that's still covering a
+		// longer-TTL entry. ExpireNX gives a set without a TTL its first
+		// one (ExpireGT treats such a set as never expiring and would leave
+		// it alone); ExpireGT then only ever extends it.
+		if ttl > 0 {
+			if err := c.client.ExpireNX(ctx, tk, ttl).Err(); err != nil {
+				return err
+			}
+			if err := c.client.ExpireGT(ctx, tk, ttl).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key).Err()
+}
+
+func (c *RedisCache) DeleteByTag(ctx context.Context, tag string) error {
+	members, err := c.client.SMembers(ctx, tagKey(tag)).Result()
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return c.client.Del(ctx, tagKey(tag)).Err()
+	}
+	keys := append(members, tagKey(tag))
+	return c.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,156 @@
+// Package crypto provides transparent AES-256-GCM encryption for PII
+// columns (internal/data's User.Email and User.Name), with support for key
+// rotation via a keyed keyring.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrKeyNotFound is returned by Decrypt when the ciphertext's key ID isn't
+// present in the Keyring.
+var ErrKeyNotFound = errors.New("crypto: key not found")
+
+// Keyring encrypts with its newest (last) key and decrypts with whichever
+// key the ciphertext's embedded key ID names, so old rows keep decrypting
+// across a key rotation. hashKey is deliberately separate from the AES
+// data keys and never rotates: HashLookup must keep producing the same
+// digest for a given value across a data-key rotation, or every row
+// written under the previous key becomes unfindable by GetByEmail until
+// -reencrypt has rewritten the whole table.
+type Keyring struct {
+	keys      map[byte][]byte
+	currentID byte
+	hashKey   []byte
+}
+
+// NewKeyring builds a Keyring from base64-encoded AES-256 data keys, in
+// rotation order (the last key is used for new encryptions, and every key
+// is kept around for decrypting data written under it; keys are ids
+// 0..len(keys)-1), plus a base64-encoded hashKey used for HashLookup that
+// is independent of data-key rotation.
+func NewKeyring(base64Keys []string, hashKey string) (*Keyring, error) {
+	if len(base64Keys) == 0 {
+		return nil, errors.New("crypto: at least one key is required")
+	}
+	if len(base64Keys) > 255 {
+		return nil, errors.New("crypto: at most 255 keys are supported")
+	}
+
+	keys := make(map[byte][]byte, len(base64Keys))
+	for i, encoded := range base64Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: decoding key %d: %w", i, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("crypto: key %d must be 32 bytes (AES-256), got %d", i, len(key))
+		}
+		keys[byte(i)] = key
+	}
+
+	decodedHashKey, err := base64.StdEncoding.DecodeString(hashKey)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: decoding hash key: %w", err)
+	}
+	if len(decodedHashKey) == 0 {
+		return nil, errors.New("crypto: hash key is required")
+	}
+
+	return &Keyring{keys: keys, currentID: byte(len(base64Keys) - 1), hashKey: decodedHashKey}, nil
+}
+
+// NewKeyringFromEnv parses a comma-separated list of base64 data keys and a
+// base64 hash key, as found in environment variables, into a Keyring.
+func NewKeyringFromEnv(keysValue, hashKeyValue string) (*Keyring, error) {
+	var encoded []string
+	for _, part := range strings.Split(keysValue, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			encoded = append(encoded, part)
+		}
+	}
+	return NewKeyring(encoded, strings.TrimSpace(hashKeyValue))
+}
+
+// Encrypt returns keyID || nonce || ciphertext, encrypted under the
+// keyring's current key.
+func (k *Keyring) Encrypt(plaintext string) ([]byte, error) {
+	key := k.keys[k.currentID]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, k.currentID)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, []byte(plaintext), nil)
+	return out, nil
+}
+
+// Decrypt reverses Encrypt, looking up the key named by the ciphertext's
+// leading key ID byte.
+func (k *Keyring) Decrypt(ciphertext []byte) (string, error) {
+	if len(ciphertext) < 1 {
+		return "", errors.New("crypto: ciphertext too short")
+	}
+
+	keyID := ciphertext[0]
+	key, ok := k.keys[keyID]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	rest := ciphertext[1:]
+	if len(rest) < gcm.NonceSize() {
+		return "", errors.New("crypto: ciphertext too short")
+	}
+	nonce, sealed := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// HashLookup returns a deterministic HMAC-SHA256 of value, keyed by the
+// keyring's stable hash key, for use as a lookup column (e.g.
+// users.email_hash) since the encrypted column itself can't be queried on
+// directly. It intentionally does not use the rotating data key, so
+// rotating k's data keys doesn't change the digest of already-stored
+// values.
+func (k *Keyring) HashLookup(value string) []byte {
+	mac := hmac.New(sha256.New, k.hashKey)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
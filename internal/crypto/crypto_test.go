@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(t *testing.T, seed byte) string {
+	t.Helper()
+	key := bytes.Repeat([]byte{seed}, 32)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestEncryptDecryptRoundtrip(t *testing.T) {
+	k, err := NewKeyring([]string{testKey(t, 1)}, testKey(t, 9))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	ciphertext, err := k.Encrypt("alice@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if plaintext != "alice@example.com" {
+		t.Fatalf("got plaintext %q, want %q", plaintext, "alice@example.com")
+	}
+}
+
+func TestDecryptUnknownKeyID(t *testing.T) {
+	k, err := NewKeyring([]string{testKey(t, 1)}, testKey(t, 9))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	ciphertext, err := k.Encrypt("alice@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// Decrypting with a key ID byte (5) that the keyring doesn't have should
+	// fail with ErrKeyNotFound, not a generic decryption error.
+	other, err := NewKeyring([]string{testKey(t, 1), testKey(t, 2)}, testKey(t, 9))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	ciphertext[0] = 5
+	if _, err := other.Decrypt(ciphertext); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Decrypt with unknown key ID: got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestRotationKeepsOldCiphertextDecryptable(t *testing.T) {
+	hashKey := testKey(t, 9)
+
+	before, err := NewKeyring([]string{testKey(t, 1)}, hashKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	ciphertext, err := before.Encrypt("alice@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// Rotate in a new current key, the way an operator adding a key to
+	// -encryption-keys would.
+	after, err := NewKeyring([]string{testKey(t, 1), testKey(t, 2)}, hashKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	plaintext, err := after.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt after rotation: %v", err)
+	}
+	if plaintext != "alice@example.com" {
+		t.Fatalf("got plaintext %q, want %q", plaintext, "alice@example.com")
+	}
+
+	// New encryptions should use the new current key, not the old one.
+	newCiphertext, err := after.Encrypt("bob@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if newCiphertext[0] != 1 {
+		t.Fatalf("got key ID %d, want the newest key (1)", newCiphertext[0])
+	}
+}
+
+func TestHashLookupStableAcrossRotation(t *testing.T) {
+	hashKey := testKey(t, 9)
+
+	before, err := NewKeyring([]string{testKey(t, 1)}, hashKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	after, err := NewKeyring([]string{testKey(t, 1), testKey(t, 2)}, hashKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	beforeHash := before.HashLookup("alice@example.com")
+	afterHash := after.HashLookup("alice@example.com")
+	if !bytes.Equal(beforeHash, afterHash) {
+		t.Fatalf("HashLookup changed across rotation: %x != %x", beforeHash, afterHash)
+	}
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	hashKey := testKey(t, 9)
+
+	if _, err := NewKeyring(nil, hashKey); err == nil {
+		t.Fatal("expected an error for an empty key list")
+	}
+	if _, err := NewKeyring([]string{base64.StdEncoding.EncodeToString([]byte("too short"))}, hashKey); err == nil {
+		t.Fatal("expected an error for a non-32-byte key")
+	}
+	if _, err := NewKeyring([]string{testKey(t, 1)}, ""); err == nil {
+		t.Fatal("expected an error for a missing hash key")
+	}
+}
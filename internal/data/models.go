@@ -1,18 +1,24 @@
 package data
 
-import "database/sql"
+import (
+	"database/sql"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/crypto"
+)
 
 type Model struct {
-	Movies      MovieModel
+	Movies      MovieStore
 	Users       UserModel
 	Tokens      TokenModel
 	Permissions PermissionModel
 }
 
-func NewModel(db *sql.DB) Model {
+// NewModel wires up the data models. keyring encrypts/decrypts the PII
+// columns on UserModel; see internal/crypto.
+func NewModel(db *sql.DB, keyring *crypto.Keyring) Model {
 	return Model{
 		Movies:      MovieModel{DB: db},
-		Users:       UserModel{DB: db},
+		Users:       UserModel{DB: db, Keyring: keyring},
 		Tokens:      TokenModel{DB: db},
 		Permissions: PermissionModel{DB: db},
 	}
@@ -44,8 +44,56 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
 }
 
+// MovieStore is satisfied by MovieModel and by CachedMovieModel, so callers
+// (and Model.Movies) don't care whether reads go straight to Postgres or
+// through a cache first.
+type MovieStore interface {
+	Insert(movie *Movie) error
+	Get(id int64) (*Movie, error)
+	Update(movie *Movie) error
+	Delete(id int64) error
+	GetAll(title string, genres []string, f Filters) ([]Movie, Metadata, error)
+}
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so a MovieModel can be
+// pointed at a transaction (e.g. cmd/greenlight-cli batching imports) just
+// by swapping in a *sql.Tx for DB.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type MovieModel struct {
-	DB *sql.DB
+	DB DBTX
+}
+
+// GetByTitleAndYear looks up a movie by its (title, year) pair, which is
+// not unique at the schema level but is the natural external key used by
+// cmd/greenlight-cli's upsert import mode.
+func (m MovieModel) GetByTitleAndYear(title string, year int32) (*Movie, error) {
+	var movie Movie
+	stmt := `SELECT id, created_at, title, year, runtime, genres, version FROM movies WHERE title = $1 AND year = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := m.DB.QueryRowContext(ctx, stmt, title, year).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Title,
+		&movie.Year,
+		&movie.Runtime,
+		pq.Array(&movie.Genres),
+		&movie.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &movie, nil
 }
 
 func (m MovieModel) Insert(movie *Movie) error {
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/cache"
+)
+
+// CacheErrors counts cache operations (Get/Set/Delete) that failed and were
+// transparently bypassed in favour of hitting the database, published
+// under cache_errors by cmd/api's SetupMetric.
+var CacheErrors = new(expvar.Int)
+
+const moviesListTag = "movies:list"
+
+// CachedMovieModel decorates a MovieStore with a read-through cache for Get
+// and GetAll. Writes invalidate the affected movie key plus the whole
+// movies:list tag, since any cached listing page could contain the
+// changed row. If the cache is unreachable, every method falls through to
+// the wrapped store rather than failing the request.
+type CachedMovieModel struct {
+	store MovieStore
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// NewCachedMovieModel wraps store with a read-through cache.Cache, caching
+// entries for ttl.
+func NewCachedMovieModel(store MovieStore, c cache.Cache, ttl time.Duration) *CachedMovieModel {
+	return &CachedMovieModel{store: store, cache: c, ttl: ttl}
+}
+
+func movieKey(id int64) string {
+	return fmt.Sprintf("movie:%d", id)
+}
+
+// movieListKey derives a stable cache key from the parameters that
+// determine a GetAll result page.
+func movieListKey(title string, genres []string, f Filters) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%v|%+v", title, genres, f)
+	return fmt.Sprintf("movies:list:%x", h.Sum(nil))
+}
+
+func (m *CachedMovieModel) Get(id int64) (*Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	key := movieKey(id)
+	if cached, ok, err := m.cache.Get(ctx, key); err != nil {
+		CacheErrors.Add(1)
+	} else if ok {
+		var movie Movie
+		if err := json.Unmarshal(cached, &movie); err == nil {
+			return &movie, nil
+		}
+	}
+
+	movie, err := m.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload, err := json.Marshal(movie); err == nil {
+		if err := m.cache.Set(ctx, key, payload, m.ttl); err != nil {
+			CacheErrors.Add(1)
+		}
+	}
+
+	return movie, nil
+}
+
+func (m *CachedMovieModel) GetAll(title string, genres []string, f Filters) ([]Movie, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	key := movieListKey(title, genres, f)
+	if cached, ok, err := m.cache.Get(ctx, key); err != nil {
+		CacheErrors.Add(1)
+	} else if ok {
+		var page struct {
+			Movies   []Movie
+			Metadata Metadata
+		}
+		if err := json.Unmarshal(cached, &page); err == nil {
+			return page.Movies, page.Metadata, nil
+		}
+	}
+
+	movies, metadata, err := m.store.GetAll(title, genres, f)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	page := struct {
+		Movies   []Movie
+		Metadata Metadata
+	}{movies, metadata}
+	if payload, err := json.Marshal(page); err == nil {
+		if err := m.cache.Set(ctx, key, payload, m.ttl, moviesListTag); err != nil {
+			CacheErrors.Add(1)
+		}
+	}
+
+	return movies, metadata, nil
+}
+
+func (m *CachedMovieModel) Insert(movie *Movie) error {
+	if err := m.store.Insert(movie); err != nil {
+		return err
+	}
+	m.invalidate(movie.ID)
+	return nil
+}
+
+func (m *CachedMovieModel) Update(movie *Movie) error {
+	if err := m.store.Update(movie); err != nil {
+		return err
+	}
+	m.invalidate(movie.ID)
+	return nil
+}
+
+func (m *CachedMovieModel) Delete(id int64) error {
+	if err := m.store.Delete(id); err != nil {
+		return err
+	}
+	m.invalidate(id)
+	return nil
+}
+
+func (m *CachedMovieModel) invalidate(id int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := m.cache.Delete(ctx, movieKey(id)); err != nil {
+		CacheErrors.Add(1)
+	}
+	if err := m.cache.DeleteByTag(ctx, moviesListTag); err != nil {
+		CacheErrors.Add(1)
+	}
+}
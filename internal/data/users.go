@@ -9,6 +9,8 @@ import (
 	"github/greenlight/internal/validator"
 	"time"
 
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/crypto"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -80,22 +82,32 @@ func ValidateUser(v *validator.Validator, user *User) {
 }
 
 type UserModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Keyring *crypto.Keyring
 }
 
 func (m UserModel) Insert(u *User) error {
-	stmt := `INSERT INTO users 
-	(name, email, password_hash, activated) VALUES
-	($1, $2, $3, $4)
+	emailCiphertext, err := m.Keyring.Encrypt(u.Email)
+	if err != nil {
+		return err
+	}
+	nameCiphertext, err := m.Keyring.Encrypt(u.Name)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO users
+	(name, email, password_hash, activated, email_hash) VALUES
+	($1, $2, $3, $4, $5)
 	RETURNING id, version;
 	`
-	args := []any{u.Name, u.Email, u.Password.hash, u.Activated}
+	args := []any{nameCiphertext, emailCiphertext, u.Password.hash, u.Activated, m.Keyring.HashLookup(u.Email)}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	row := m.DB.QueryRowContext(ctx, stmt, args...)
 	if err := row.Scan(&u.ID, &u.Version); err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_hash_key"`:
 			return ErrDuplicateEmail
 		default:
 			return err
@@ -107,16 +119,17 @@ func (m UserModel) Insert(u *User) error {
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	stmt := `SELECT id, created_at, name, email, password_hash, activated, version
 	FROM users
-	WHERE email = $1
+	WHERE email_hash = $1
 	 `
 	var user User
+	var emailCiphertext, nameCiphertext []byte
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	err := m.DB.QueryRowContext(ctx, stmt, email).Scan(
+	err := m.DB.QueryRowContext(ctx, stmt, m.Keyring.HashLookup(email)).Scan(
 		&user.ID,
 		&user.CreatedAt,
-		&user.Name,
-		&user.Email,
+		&nameCiphertext,
+		&emailCiphertext,
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
@@ -129,23 +142,36 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 			return nil, err
 		}
 	}
+
+	if err := m.decrypt(&user, nameCiphertext, emailCiphertext); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
 func (m UserModel) Update(user *User) error {
-	stmt := `UPDATE users 
+	emailCiphertext, err := m.Keyring.Encrypt(user.Email)
+	if err != nil {
+		return err
+	}
+	nameCiphertext, err := m.Keyring.Encrypt(user.Name)
+	if err != nil {
+		return err
+	}
+
+	stmt := `UPDATE users
 	SET name = $1 , email = $2, password_hash = $3,activated = $4,
-	version = version + 1
-	WHERE id = $5 AND version=$6
+	version = version + 1, email_hash = $5
+	WHERE id = $6 AND version=$7
 	RETURNING version
 	 `
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.ID, user.Version}
+	args := []any{nameCiphertext, emailCiphertext, user.Password.hash, user.Activated, m.Keyring.HashLookup(user.Email), user.ID, user.Version}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	err := m.DB.QueryRowContext(ctx, stmt, args...).Scan(&user.Version)
+	err = m.DB.QueryRowContext(ctx, stmt, args...).Scan(&user.Version)
 	if err != nil {
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_hash_key"`:
 			return ErrDuplicateEmail
 		case errors.Is(sql.ErrNoRows, err):
 			return ErrEditConflict
@@ -170,10 +196,11 @@ func (m UserModel) GetForToken(tokenScope string, tokenPlaintext string) (*User,
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	var user User
+	var emailCiphertext, nameCiphertext []byte
 	err := m.DB.QueryRowContext(ctx, stmt, args...).Scan(&user.ID,
 		&user.CreatedAt,
-		&user.Name,
-		&user.Email,
+		&nameCiphertext,
+		&emailCiphertext,
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
@@ -187,9 +214,28 @@ func (m UserModel) GetForToken(tokenScope string, tokenPlaintext string) (*User,
 			return nil, err
 		}
 	}
+
+	if err := m.decrypt(&user, nameCiphertext, emailCiphertext); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
+// decrypt fills in user.Name and user.Email from their ciphertext columns.
+func (m UserModel) decrypt(user *User, nameCiphertext, emailCiphertext []byte) error {
+	name, err := m.Keyring.Decrypt(nameCiphertext)
+	if err != nil {
+		return err
+	}
+	email, err := m.Keyring.Decrypt(emailCiphertext)
+	if err != nil {
+		return err
+	}
+	user.Name = name
+	user.Email = email
+	return nil
+}
+
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
\ No newline at end of file
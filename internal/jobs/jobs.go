@@ -0,0 +1,87 @@
+// Package jobs provides a Postgres-backed job queue that survives process
+// restarts and panics, for work (activation emails, password-reset emails,
+// ...) that used to run as fire-and-forget goroutines via app.background.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Job is a single unit of work persisted in the jobs table.
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     json.RawMessage
+	RunAfter    time.Time
+	Attempts    int
+	MaxAttempts int
+	LastError   sql.NullString
+	LockedAt    sql.NullTime
+	LockedBy    sql.NullString
+	CreatedAt   time.Time
+}
+
+// Handler processes the payload for one job kind. A returned error marks
+// the job as failed; the worker will retry it with backoff until
+// MaxAttempts is reached, at which point it is left dead-lettered.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// EnqueueOption customises a single Enqueue call.
+type EnqueueOption func(*enqueueOptions)
+
+type enqueueOptions struct {
+	runAfter    time.Time
+	maxAttempts int
+}
+
+// RunAfter delays a job's first claim until t.
+func RunAfter(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) { o.runAfter = t }
+}
+
+// MaxAttempts overrides the default retry budget before a job is
+// dead-lettered.
+func MaxAttempts(n int) EnqueueOption {
+	return func(o *enqueueOptions) { o.maxAttempts = n }
+}
+
+// JobQueue is the Postgres-backed queue that handlers enqueue work onto and
+// that Worker claims work from.
+type JobQueue struct {
+	DB *sql.DB
+}
+
+// NewQueue returns a JobQueue backed by db.
+func NewQueue(db *sql.DB) *JobQueue {
+	return &JobQueue{DB: db}
+}
+
+// Enqueue persists a new job of the given kind. payload is marshalled to
+// JSON and stored in the jobs.payload column.
+func (q *JobQueue) Enqueue(kind string, payload any, opts ...EnqueueOption) error {
+	options := enqueueOptions{
+		runAfter:    time.Now(),
+		maxAttempts: 5,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO jobs (kind, payload, run_after, max_attempts)
+	VALUES ($1, $2, $3, $4)`
+	args := []any{kind, payloadJSON, options.runAfter, options.maxAttempts}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = q.DB.ExecContext(ctx, stmt, args...)
+	return err
+}
@@ -0,0 +1,220 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Ahmed-Abdel-karim/greenlight/internal/jsonlog"
+)
+
+// staleLockTimeout bounds how long a job may stay locked before another
+// worker is allowed to reclaim it, so a crashed instance doesn't strand
+// jobs forever.
+const staleLockTimeout = 5 * time.Minute
+
+// Counters published under jobs_processed/jobs_failed/jobs_in_flight by
+// cmd/api's SetupMetric.
+var (
+	Processed = new(expvar.Int)
+	Failed    = new(expvar.Int)
+	InFlight  = new(expvar.Int)
+)
+
+// Worker claims and executes jobs from a JobQueue using a registered set of
+// per-kind Handlers.
+type Worker struct {
+	queue        *JobQueue
+	logger       *jsonlog.Logger
+	id           string
+	handlers     map[string]Handler
+	pollInterval time.Duration
+
+	shutdown chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewWorker returns a Worker that has not yet been started.
+func NewWorker(queue *JobQueue, logger *jsonlog.Logger, id string) *Worker {
+	return &Worker{
+		queue:        queue,
+		logger:       logger,
+		id:           id,
+		handlers:     make(map[string]Handler),
+		pollInterval: time.Second,
+		shutdown:     make(chan struct{}),
+	}
+}
+
+// Register associates a Handler with a job kind. Registering the same kind
+// twice overwrites the previous handler.
+func (w *Worker) Register(kind string, h Handler) {
+	w.handlers[kind] = h
+}
+
+// Start launches n goroutines that poll the queue and dispatch claimed jobs
+// to their registered handler.
+func (w *Worker) Start(n int) {
+	for i := 0; i < n; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+}
+
+// Shutdown signals the worker pool to stop claiming new jobs and waits for
+// in-flight jobs to finish, up to ctx's deadline. Jobs still running past
+// that deadline remain locked until staleLockTimeout elapses, at which
+// point another instance's claim query will reclaim them.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	close(w.shutdown)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Worker) run() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.shutdown:
+			return
+		case <-ticker.C:
+			for w.processOne() {
+				// Drain the queue before sleeping again.
+			}
+		}
+	}
+}
+
+// processOne claims and runs a single job. It reports whether a job was
+// claimed, so the caller can keep draining without waiting for the ticker.
+func (w *Worker) processOne() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	job, err := w.claim(ctx)
+	if err != nil {
+		w.logger.PrintError(err, nil)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	InFlight.Add(1)
+	defer InFlight.Add(-1)
+
+	handler, ok := w.handlers[job.Kind]
+	if !ok {
+		w.fail(job, fmt.Errorf("no handler registered for job kind %q", job.Kind))
+		return true
+	}
+
+	runCtx, runCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer runCancel()
+
+	if err := handler(runCtx, job.Payload); err != nil {
+		w.fail(job, err)
+		return true
+	}
+
+	w.complete(job)
+	return true
+}
+
+func (w *Worker) claim(ctx context.Context) (*Job, error) {
+	tx, err := w.queue.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, kind, payload, attempts, max_attempts
+	FROM jobs
+	WHERE run_after <= NOW()
+	AND attempts < max_attempts
+	AND (locked_at IS NULL OR locked_at < NOW() - $1::interval)
+	ORDER BY run_after
+	FOR UPDATE SKIP LOCKED
+	LIMIT 1`
+
+	var job Job
+	row := tx.QueryRowContext(ctx, stmt, staleLockTimeout.String())
+	err = row.Scan(&job.ID, &job.Kind, &job.Payload, &job.Attempts, &job.MaxAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE jobs SET locked_at = NOW(), locked_by = $1 WHERE id = $2`, w.id, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (w *Worker) complete(job *Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := w.queue.DB.ExecContext(ctx, `DELETE FROM jobs WHERE id = $1`, job.ID)
+	if err != nil {
+		w.logger.PrintError(err, map[string]string{"job_id": fmt.Sprintf("%d", job.ID)})
+		return
+	}
+	Processed.Add(1)
+}
+
+// fail records the error, bumps the attempt count and reschedules the job
+// with exponential backoff. Once attempts reaches max_attempts the job is
+// left unlocked in the table, i.e. dead-lettered: the claim query's
+// `attempts < max_attempts` guard stops it being picked up again, whatever
+// its run_after.
+func (w *Worker) fail(job *Job, jobErr error) {
+	Failed.Add(1)
+	w.logger.PrintError(jobErr, map[string]string{
+		"job_id": fmt.Sprintf("%d", job.ID),
+		"kind":   job.Kind,
+	})
+
+	attempts := job.Attempts + 1
+	backoff := time.Duration(1<<attempts) * time.Second
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stmt := `UPDATE jobs
+	SET attempts = $1, last_error = $2, run_after = NOW() + $3::interval, locked_at = NULL, locked_by = NULL
+	WHERE id = $4`
+	_, err := w.queue.DB.ExecContext(ctx, stmt, attempts, jobErr.Error(), backoff.String(), job.ID)
+	if err != nil {
+		w.logger.PrintError(err, map[string]string{"job_id": fmt.Sprintf("%d", job.ID)})
+	}
+}
@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migration files into the compiled binary
+// so that greenlight can manage its own schema without shipping the
+// migrations/ directory alongside it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS